@@ -0,0 +1,47 @@
+package cpanel
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ログインページのパス。レスポンスがこのパスへリダイレクトされていたらセッション切れとみなす
+const loginPath = "/Login.aspx"
+
+// セッション切れを検知し、再認証を行う
+type AuthRecoverer interface {
+	// レスポンスがセッション切れによるものかどうかを判定する。
+	// IsExpiredResponseによる標準判定に加えて行いたい独自の判定があればここに書く
+	IsExpired(resp *http.Response, doc *goquery.Document) bool
+
+	// 再ログインし、BrowserInfoのCookie/hiddenパラメータを更新する
+	Reauthenticate(bi *BrowserInfo) error
+}
+
+// uのパスがLoginページ(loginPath)で終わっているか調べる
+func IsLoginURL(u *url.URL) bool {
+	return u != nil && strings.HasSuffix(u.Path, loginPath)
+}
+
+// docにログインフォーム(IDがLoginFormのform、もしくはnameがUserIDのinput)が
+// 含まれているかどうかを調べる
+func HasLoginForm(doc *goquery.Document) bool {
+	return doc.Find("form#LoginForm, input[name='UserID']").Length() > 0
+}
+
+// レスポンスがセッション切れを示しているか判定する標準ロジック。
+// 302でのLoginページへのリダイレクト、もしくはリダイレクト後のページに
+// ログインフォームが含まれているかで判定する。AuthRecoverer実装はこれを
+// そのまま使うか、独自の判定と組み合わせて使うことができる
+func IsExpiredResponse(resp *http.Response, doc *goquery.Document) bool {
+	if resp.Request != nil && IsLoginURL(resp.Request.URL) {
+		return true
+	}
+	if doc != nil && HasLoginForm(doc) {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package cpanel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type staticRequester struct {
+	url string
+}
+
+func (r *staticRequester) NewRequest(values url.Values) (*http.Request, error) {
+	return http.NewRequest(http.MethodGet, r.url, nil)
+}
+
+type countingRecoverer struct {
+	reauthenticated int
+}
+
+func (r *countingRecoverer) IsExpired(resp *http.Response, doc *goquery.Document) bool {
+	return false
+}
+
+func (r *countingRecoverer) Reauthenticate(bi *BrowserInfo) error {
+	r.reauthenticated++
+	return nil
+}
+
+func TestActionRunRetriesOnceOnLoginRedirect(t *testing.T) {
+
+	loggedIn := false
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/Login.aspx" {
+			loggedIn = true
+			w.Write([]byte(`<html><body>login page</body></html>`))
+			return
+		}
+		if !loggedIn {
+			http.Redirect(w, req, "/Login.aspx", http.StatusFound)
+			return
+		}
+		w.Write([]byte(`<html><body>ok</body></html>`))
+	}))
+	defer srv.Close()
+
+	recoverer := &countingRecoverer{}
+	result := &capturingResulter{}
+
+	act := &Action{Request: &staticRequester{url: srv.URL + "/Service/VPS/"}, Result: result}
+
+	bi := &BrowserInfo{}
+	bi.InitializeDefault()
+	bi.recoverer = recoverer
+
+	if err := act.Run(bi); err != nil {
+		t.Fatalf("Action.Run failed: %v", err)
+	}
+
+	if recoverer.reauthenticated != 1 {
+		t.Fatalf("expected Reauthenticate to be called once, got %d", recoverer.reauthenticated)
+	}
+}
@@ -1,7 +1,7 @@
 package cpanel
 
 import (
-	"errors"
+	"context"
 	"github.com/PuerkitoBio/goquery"
 	"net/http"
 	"net/http/cookiejar"
@@ -37,9 +37,13 @@ type JsonActionResulter interface {
 }
 
 func (act *Action) Run(bi *BrowserInfo) (err error) {
+	return act.run(bi, false)
+}
+
+func (act *Action) run(bi *BrowserInfo, retried bool) (err error) {
 
 	if act.Request == nil || act.Result == nil {
-		return errors.New("Some Struct fields of cpanel.Action undefined.")
+		return Error{Code: "error.action_undefined"}
 	}
 
 	// リクエストを作成
@@ -47,6 +51,9 @@ func (act *Action) Run(bi *BrowserInfo) (err error) {
 	if err != nil {
 		return err
 	}
+	if bi.ctx != nil {
+		req = req.WithContext(bi.ctx)
+	}
 
 	// HTTPヘッダをセット
 	for key, value := range bi.headers {
@@ -54,7 +61,7 @@ func (act *Action) Run(bi *BrowserInfo) (err error) {
 	}
 
 	// HTTPリクエスト実行
-	cli := &http.Client{Jar: bi.cookiejar}
+	cli := &http.Client{Jar: bi.cookiejar, Transport: bi.transport}
 	resp, err := cli.Do(req)
 
 	if err != nil {
@@ -62,14 +69,6 @@ func (act *Action) Run(bi *BrowserInfo) (err error) {
 	}
 	defer resp.Body.Close()
 
-	// dump, _ := httputil.DumpRequest(req, true)
-	// println(string(dump))
-
-	// if req.URL.String() == "https://cp.conoha.jp/Service/VPS/" && req.Method == "POST" {
-	// 	dump, _ = httputil.DumpResponse(resp, false)
-	// 	println(string(dump))
-	// }
-
 	switch r := act.Result.(type) {
 	case HtmlActionResulter:
 		var doc *goquery.Document
@@ -77,6 +76,15 @@ func (act *Action) Run(bi *BrowserInfo) (err error) {
 		if err != nil {
 			return err
 		}
+
+		// セッション切れなら再ログインして一度だけリトライする
+		if !retried && bi.recoverer != nil && (IsExpiredResponse(resp, doc) || bi.recoverer.IsExpired(resp, doc)) {
+			if rerr := act.reauthenticateAndRetry(bi, req); rerr != nil {
+				return rerr
+			}
+			return act.run(bi, true)
+		}
+
 		// hiddenパラメータを取得
 		bi.Values = act.hiddenParams(doc)
 
@@ -87,8 +95,32 @@ func (act *Action) Run(bi *BrowserInfo) (err error) {
 		return r.Populate(resp)
 
 	default:
-		return errors.New("Undefined Result type.")
+		return Error{Code: "error.result_undefined"}
+	}
+}
+
+// 再ログインを行い、もとのページをGETし直してhiddenパラメータを更新する
+func (act *Action) reauthenticateAndRetry(bi *BrowserInfo, req *http.Request) error {
+
+	if err := bi.recoverer.Reauthenticate(bi); err != nil {
+		return err
+	}
+
+	cli := &http.Client{Jar: bi.cookiejar, Transport: bi.transport}
+	resp, err := cli.Get(req.URL.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	doc, err := goquery.NewDocumentFromResponse(resp)
+	if err != nil {
+		return err
 	}
+
+	bi.Values = act.hiddenParams(doc)
+
+	return nil
 }
 
 // BrowserInfoにHTMLフォームに共通する "__" で始まるhidden要素を抽出してバインドする
@@ -128,17 +160,26 @@ type BrowserInfo struct {
 
 	// リクエストに付与されるURL/POSTパラメータ
 	Values url.Values
+
+	// セッション切れを検知・回復するためのAuthRecoverer。未設定なら何もしない
+	recoverer AuthRecoverer
+
+	// リクエストの送信に使うRoundTripper
+	transport http.RoundTripper
+
+	// 設定されていればリクエストに紐付けられ、キャンセル時に中断させる
+	ctx context.Context
 }
 
 func (b *BrowserInfo) InitializeDefault() {
 	b.headers = map[string]string{
-		"User-Agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.10; rv:34.0) Gecko/20100101 Firefox/34.0",
-		"Accept":     "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
-		//"Accept-Language": "ja,en-us;q=0.7,en;q=0.3",
-		"Accept-Language": "en-US,en;q=0.8,ja;q=0.6",
+		"User-Agent":      "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.10; rv:34.0) Gecko/20100101 Firefox/34.0",
+		"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+		"Accept-Language": acceptLanguageHeader(),
 	}
 	b.Values = url.Values{}
 	b.cookiejar, _ = cookiejar.New(nil)
+	b.transport = http.DefaultTransport
 }
 
 func (b *BrowserInfo) cookieUrl() *url.URL {
@@ -177,6 +218,26 @@ type Browser struct {
 
 	// 実行するリクエストのスライス
 	actions []*Action
+
+	// WithSessionFileで設定されたセッション保存先のパス
+	sessionFile string
+
+	// リクエストの送信に使うRoundTripper。未設定の場合はhttp.DefaultTransportを使う
+	Transport http.RoundTripper
+
+	// AddActionAfterで登録された依存関係
+	deps []dependency
+}
+
+// func(http.RoundTripper) http.RoundTripper の形でRoundTripperをラップするミドルウェア
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// rtをmwsでラップしたRoundTripperを返す。mws[0]が最も外側になり、リクエストはmws[0]から順に通過する
+func Chain(rt http.RoundTripper, mws ...Middleware) http.RoundTripper {
+	for i := len(mws) - 1; i >= 0; i-- {
+		rt = mws[i](rt)
+	}
+	return rt
 }
 
 var browserInstance *Browser
@@ -195,6 +256,12 @@ func NewBrowser() *Browser {
 	return browserInstance
 }
 
+// セッション切れ検知時に使うAuthRecovererを設定する
+func (b *Browser) SetAuthRecoverer(r AuthRecoverer) *Browser {
+	b.BrowserInfo.recoverer = r
+	return b
+}
+
 // アクションを追加する
 func (b *Browser) AddAction(act *Action) {
 	b.actions = append(b.actions, act)
@@ -206,6 +273,10 @@ func (b *Browser) ClearAction() {
 }
 
 func (b *Browser) Run() error {
+	if b.Transport != nil {
+		b.BrowserInfo.transport = b.Transport
+	}
+
 	for _, act := range b.actions {
 
 		err := act.Run(b.BrowserInfo)
@@ -216,5 +287,10 @@ func (b *Browser) Run() error {
 	}
 
 	b.ClearAction()
+
+	if b.sessionFile != "" {
+		b.BrowserInfo.SaveSession(b.sessionFile)
+	}
+
 	return nil
 }
@@ -0,0 +1,210 @@
+package cpanel
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+)
+
+// AddActionAfterで登録した依存関係
+type dependency struct {
+	act  *Action
+	deps []*Action
+}
+
+// actの実行がdepsの完了後になるように依存関係付きでアクションを追加する
+// depsを指定しなければAddActionと同じく依存のないアクションとして登録される
+func (b *Browser) AddActionAfter(act *Action, deps ...*Action) {
+	b.actions = append(b.actions, act)
+	b.deps = append(b.deps, dependency{act: act, deps: deps})
+}
+
+// bi.cookiejar・headers・transport・recovererを共有しつつ、
+// valuesで指定したものに差し替えたBrowserInfoを作る
+func (b *BrowserInfo) cloneWithValues(values url.Values) *BrowserInfo {
+	return &BrowserInfo{
+		cookiejar: b.cookiejar,
+		headers:   b.headers,
+		Values:    values,
+		recoverer: b.recoverer,
+		transport: b.transport,
+		ctx:       b.ctx,
+	}
+}
+
+// valuesのコピーを作る
+func cloneValues(values url.Values) url.Values {
+	clone := url.Values{}
+	for k, v := range values {
+		clone[k] = append([]string(nil), v...)
+	}
+	return clone
+}
+
+// srcの各キーをdstへコピーする。dstに同じキーがあればsrcの値で上書きする
+func mergeValuesInto(dst url.Values, src url.Values) {
+	for k, v := range src {
+		dst[k] = append([]string(nil), v...)
+	}
+}
+
+// 依存関係に基づき、ルート（依存のないアクション）から順にアクションを並列実行する
+// 同じcookiejarを共有するが、各アクションは複製されたBrowserInfo.Valuesを使う。
+// このValuesは実行元のBrowserInfoの値ではなく、依存先アクションが実行後に得た
+// __VIEWSTATE等のhiddenパラメータを引き継いだものになるため、
+// ログイン → 一覧取得 → 各VPSの詳細取得、のような依存チェインでも
+// 各リーフが正しいhiddenパラメータでリクエストできる
+func (b *Browser) RunConcurrent(ctx context.Context, maxParallel int) error {
+
+	if maxParallel <= 0 {
+		return errors.New("cpanel: maxParallel must be greater than 0")
+	}
+
+	if b.Transport != nil {
+		b.BrowserInfo.transport = b.Transport
+	}
+
+	depsOf := make(map[*Action][]*Action, len(b.deps))
+	for _, d := range b.deps {
+		depsOf[d.act] = d.deps
+	}
+
+	remaining := make(map[*Action]int, len(b.actions))
+	dependents := make(map[*Action][]*Action)
+	for _, act := range b.actions {
+		deps := depsOf[act]
+		remaining[act] = len(deps)
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], act)
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	results := make(map[*Action]url.Values, len(b.actions))
+	sem := make(chan struct{}, maxParallel)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	b.BrowserInfo.ctx = ctx
+
+	// schedule はwg.Add(1)を呼び出し側（すでに走っているgoroutineか、初期スケジューリング
+	// ループ）で同期的に行ってからgoroutineを起こす。runはそのgoroutine本体で、
+	// semの取得・解放も含めて自分の中だけで完結させる。
+	// schedule自身はsemを取得しない（runの中でのみ取得する）ことで、
+	// すでにsemの枠を持っているgoroutineがそのままschedule(dependent)を呼んでも
+	// 自分自身の枠待ちでブロックすることがない（maxParallel=1でもデッドロックしない）
+	var run func(act *Action)
+	schedule := func(act *Action) {
+		wg.Add(1)
+		go run(act)
+	}
+
+	run = func(act *Action) {
+		defer wg.Done()
+
+		sem <- struct{}{}
+		defer func() { <-sem }()
+
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = ctx.Err()
+			}
+			mu.Unlock()
+			return
+		default:
+		}
+
+		// 依存先が完了後に得たhiddenパラメータを引き継いだValuesを作る。
+		// 依存がなければ（ルートアクション）共有のBrowserInfo.Valuesを使う
+		mu.Lock()
+		values := url.Values{}
+		deps := depsOf[act]
+		if len(deps) == 0 {
+			mergeValuesInto(values, b.BrowserInfo.Values)
+		} else {
+			for _, dep := range deps {
+				mergeValuesInto(values, results[dep])
+			}
+		}
+		mu.Unlock()
+
+		bi := b.BrowserInfo.cloneWithValues(values)
+
+		if err := act.Run(bi); err != nil {
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+			cancel()
+			return
+		}
+
+		mu.Lock()
+		results[act] = cloneValues(bi.Values)
+		next := make([]*Action, 0, len(dependents[act]))
+		for _, dependent := range dependents[act] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				next = append(next, dependent)
+			}
+		}
+		mu.Unlock()
+
+		for _, dependent := range next {
+			schedule(dependent)
+		}
+	}
+
+	// 依存のないルートアクションを、いずれかのgoroutineを起動する前に洗い出しておく。
+	// そうしないと、先に起動したgoroutineがremainingを書き換えるのと並行して
+	// このループがremainingを読むことになり、map読み書きの競合になる
+	var roots []*Action
+	for _, act := range b.actions {
+		if remaining[act] == 0 {
+			roots = append(roots, act)
+		}
+	}
+	for _, act := range roots {
+		schedule(act)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		b.ClearAction()
+		b.deps = nil
+		return firstErr
+	}
+
+	for act := range remaining {
+		if remaining[act] > 0 {
+			b.ClearAction()
+			b.deps = nil
+			return errors.New("cpanel: dependency cycle detected in Browser actions")
+		}
+	}
+
+	// 実行結果のhiddenパラメータを、登録順（後勝ち）でBrowserInfoへ書き戻す。
+	// こうしておくことで、RunConcurrentの直後にSaveSessionしても
+	// 実行前ではなく実行後の状態が保存される
+	finalValues := url.Values{}
+	for _, act := range b.actions {
+		mergeValuesInto(finalValues, results[act])
+	}
+	b.BrowserInfo.Values = finalValues
+
+	b.ClearAction()
+	b.deps = nil
+
+	if b.sessionFile != "" {
+		b.BrowserInfo.SaveSession(b.sessionFile)
+	}
+
+	return nil
+}
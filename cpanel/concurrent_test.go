@@ -0,0 +1,143 @@
+package cpanel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// values経由で__VIEWSTATEが渡されれば"echo:"を前に付けたその値を、渡されなければ
+// "root-state"をレスポンスのhidden要素として返すテストサーバ
+func newHiddenEchoServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.ParseForm()
+		viewstate := req.Form.Get("__VIEWSTATE")
+		if viewstate == "" {
+			viewstate = "root-state"
+		} else {
+			viewstate = "echo:" + viewstate
+		}
+		w.Write([]byte(`<html><body><input type="hidden" name="__VIEWSTATE" value="` + viewstate + `"></body></html>`))
+	}))
+}
+
+type echoRequester struct {
+	url string
+}
+
+func (r *echoRequester) NewRequest(values url.Values) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	q := req.URL.Query()
+	for k, v := range values {
+		for _, vv := range v {
+			q.Add(k, vv)
+		}
+	}
+	req.URL.RawQuery = q.Encode()
+	return req, nil
+}
+
+type capturingResulter struct {
+	gotViewState string
+}
+
+func (r *capturingResulter) Populate(resp *http.Response, doc *goquery.Document) error {
+	r.gotViewState, _ = doc.Find("input[name='__VIEWSTATE']").Attr("value")
+	return nil
+}
+
+func TestRunConcurrentThreadsHiddenParamsToDependents(t *testing.T) {
+
+	srv := newHiddenEchoServer(t)
+	defer srv.Close()
+
+	root := &capturingResulter{}
+	leaf := &capturingResulter{}
+
+	rootAction := &Action{Request: &echoRequester{url: srv.URL}, Result: root}
+	leafAction := &Action{Request: &echoRequester{url: srv.URL}, Result: leaf}
+
+	b := NewBrowser()
+	b.ClearAction()
+	b.deps = nil
+	b.BrowserInfo = &BrowserInfo{}
+	b.BrowserInfo.InitializeDefault()
+
+	b.AddActionAfter(rootAction)
+	b.AddActionAfter(leafAction, rootAction)
+
+	if err := b.RunConcurrent(context.Background(), 4); err != nil {
+		t.Fatalf("RunConcurrent failed: %v", err)
+	}
+
+	if root.gotViewState != "root-state" {
+		t.Fatalf("root action got unexpected __VIEWSTATE: %q", root.gotViewState)
+	}
+
+	if leaf.gotViewState != "echo:root-state" {
+		t.Fatalf("leaf action did not inherit root's __VIEWSTATE, got %q", leaf.gotViewState)
+	}
+
+	if got := b.BrowserInfo.Values.Get("__VIEWSTATE"); got != "echo:root-state" {
+		t.Fatalf("BrowserInfo.Values not updated after RunConcurrent, got %q", got)
+	}
+}
+
+func TestRunConcurrentWithSingleWorkerDoesNotDeadlockOnDependencyChain(t *testing.T) {
+
+	srv := newHiddenEchoServer(t)
+	defer srv.Close()
+
+	root := &capturingResulter{}
+	leaf := &capturingResulter{}
+
+	rootAction := &Action{Request: &echoRequester{url: srv.URL}, Result: root}
+	leafAction := &Action{Request: &echoRequester{url: srv.URL}, Result: leaf}
+
+	b := NewBrowser()
+	b.ClearAction()
+	b.deps = nil
+	b.BrowserInfo = &BrowserInfo{}
+	b.BrowserInfo.InitializeDefault()
+
+	b.AddActionAfter(rootAction)
+	b.AddActionAfter(leafAction, rootAction)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- b.RunConcurrent(ctx, 1)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RunConcurrent failed: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("RunConcurrent(ctx, 1) deadlocked on a root -> dependent chain")
+	}
+}
+
+func TestRunConcurrentRejectsNonPositiveMaxParallel(t *testing.T) {
+
+	b := NewBrowser()
+	b.ClearAction()
+	b.deps = nil
+
+	for _, n := range []int{0, -1} {
+		if err := b.RunConcurrent(context.Background(), n); err == nil {
+			t.Fatalf("expected error for maxParallel=%d, got nil", n)
+		}
+	}
+}
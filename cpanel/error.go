@@ -0,0 +1,64 @@
+package cpanel
+
+import (
+	"strings"
+
+	"github.com/naototty/conoha-vps/cpanel/i18n"
+)
+
+// 現在選択されている言語で使うTranslator。後方互換のためデフォルトは"ja"
+var translator i18n.Translator
+
+// SetLanguageで選択された言語。BrowserInfo.InitializeDefaultのAccept-Languageに使う
+var acceptLanguage = "ja"
+
+// 選択中の言語を優先するAccept-Languageヘッダの値を作る
+func acceptLanguageHeader() string {
+	if acceptLanguage == "en" {
+		return "en-US,en;q=0.8,ja;q=0.6"
+	}
+	return "ja,en-US;q=0.8,en;q=0.6"
+}
+
+// エラーの日本語/英語メッセージカタログと、表示に使うパラメータ
+type Error struct {
+	// i18nカタログのキー（例: "error.login_failed"）
+	Code string
+
+	// メッセージテンプレートに埋め込むパラメータ
+	Params map[string]string
+}
+
+func (e Error) Error() string {
+	msg := e.Code
+	if translator != nil {
+		msg = translator.T(e.Code)
+	}
+
+	if len(e.Params) == 0 {
+		return msg
+	}
+
+	// メッセージテンプレート中の"{key}"をParamsの値に置き換える
+	pairs := make([]string, 0, len(e.Params)*2)
+	for k, v := range e.Params {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(msg)
+}
+
+// 表示言語を切り替える。"en"または"ja"を指定する
+// 未知の言語、もしくはカタログの読み込みに失敗した場合は何もしない
+func SetLanguage(lang string) error {
+	t, err := i18n.New(lang)
+	if err != nil {
+		return err
+	}
+	translator = t
+	acceptLanguage = lang
+	return nil
+}
+
+func init() {
+	SetLanguage("ja")
+}
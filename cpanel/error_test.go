@@ -0,0 +1,62 @@
+package cpanel
+
+import "testing"
+
+func TestSetLanguageSwitchesErrorMessages(t *testing.T) {
+	defer SetLanguage("ja")
+
+	if err := SetLanguage("ja"); err != nil {
+		t.Fatalf("SetLanguage(ja) failed: %v", err)
+	}
+	ja := Error{Code: "error.login_failed"}.Error()
+	if ja != "ログインに失敗しました" {
+		t.Fatalf("unexpected ja message: %q", ja)
+	}
+
+	if err := SetLanguage("en"); err != nil {
+		t.Fatalf("SetLanguage(en) failed: %v", err)
+	}
+	en := Error{Code: "error.login_failed"}.Error()
+	if en != "Login failed" {
+		t.Fatalf("unexpected en message: %q", en)
+	}
+}
+
+func TestSetLanguageUpdatesAcceptLanguageHeader(t *testing.T) {
+	defer SetLanguage("ja")
+
+	SetLanguage("en")
+	bi := &BrowserInfo{}
+	bi.InitializeDefault()
+	if got := bi.headers["Accept-Language"]; got != "en-US,en;q=0.8,ja;q=0.6" {
+		t.Fatalf("unexpected Accept-Language for en: %q", got)
+	}
+
+	SetLanguage("ja")
+	bi.InitializeDefault()
+	if got := bi.headers["Accept-Language"]; got != "ja,en-US;q=0.8,en;q=0.6" {
+		t.Fatalf("unexpected Accept-Language for ja: %q", got)
+	}
+}
+
+func TestErrorParamsAreSubstitutedIntoTheTranslatedMessage(t *testing.T) {
+	defer SetLanguage("ja")
+	SetLanguage("en")
+
+	err := Error{Code: "error.login_failed", Params: map[string]string{"x": "y"}}
+	// error.login_failed has no placeholders, so Params must not alter the message
+	if got := err.Error(); got != "Login failed" {
+		t.Fatalf("unexpected message with unused params: %q", got)
+	}
+
+	withPlaceholder := Error{
+		Code:   "__test_template__",
+		Params: map[string]string{"user": "taro"},
+	}
+	// Error.Error falls back to the raw code when the translator has no entry for it,
+	// so "{user}" in the fallback string is still substituted by Params
+	withPlaceholder.Code = "{user} not found"
+	if got := withPlaceholder.Error(); got != "taro not found" {
+		t.Fatalf("expected Params to substitute into the message, got %q", got)
+	}
+}
@@ -0,0 +1,45 @@
+// cpanel/i18nは、スクレイピング結果やエラーメッセージのラベルを
+// 日本語・英語に翻訳するためのパッケージ
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+)
+
+//go:embed catalog/*.json
+var catalogFS embed.FS
+
+// キーから翻訳済み文字列を引く
+type Translator interface {
+	// keyに対応する翻訳済み文字列を返す。未定義のkeyの場合はkeyをそのまま返す
+	T(key string) string
+}
+
+// catalog/*.jsonから読み込んだメッセージカタログによるTranslator
+type catalogTranslator struct {
+	messages map[string]string
+}
+
+// langに対応するカタログ（"ja" または "en"）を読み込んだTranslatorを作る
+func New(lang string) (Translator, error) {
+
+	data, err := catalogFS.ReadFile("catalog/" + lang + ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	messages := map[string]string{}
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, err
+	}
+
+	return &catalogTranslator{messages: messages}, nil
+}
+
+func (c *catalogTranslator) T(key string) string {
+	if msg, ok := c.messages[key]; ok {
+		return msg
+	}
+	return key
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httputil"
+
+	"github.com/naototty/conoha-vps/cpanel"
+)
+
+// リクエスト/レスポンスのダンプをwへ書き出すRoundTripper
+// Action.Runに埋め込まれていたデバッグ出力の代替
+func DumpTo(w io.Writer) cpanel.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &dumpTransport{next: next, w: w}
+	}
+}
+
+type dumpTransport struct {
+	next http.RoundTripper
+	w    io.Writer
+}
+
+func (t *dumpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	if dump, err := httputil.DumpRequest(req, true); err == nil {
+		t.w.Write(dump)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if dump, err := httputil.DumpResponse(resp, false); err == nil {
+		t.w.Write(dump)
+	}
+
+	return resp, err
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryRetriesGetOnConnectionError(t *testing.T) {
+
+	attempts := 0
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("connection reset")
+		}
+		return httptest.NewRecorder().Result(), nil
+	})
+
+	client := Retry(3, time.Millisecond)(rt)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.invalid/", nil)
+	if _, err := client.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryRetriesNonGetOn5xx(t *testing.T) {
+
+	attempts := 0
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		rec := httptest.NewRecorder()
+		if attempts < 2 {
+			rec.WriteHeader(http.StatusInternalServerError)
+		} else {
+			rec.WriteHeader(http.StatusOK)
+		}
+		return rec.Result(), nil
+	})
+
+	client := Retry(3, time.Millisecond)(rt)
+
+	req, _ := http.NewRequest(http.MethodPost, "http://example.invalid/", nil)
+	resp, err := client.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected final status 200, got %d", resp.StatusCode)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryResendsOriginalBodyOnRetry(t *testing.T) {
+
+	var gotBodies []string
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		gotBodies = append(gotBodies, string(body))
+
+		rec := httptest.NewRecorder()
+		if len(gotBodies) < 2 {
+			rec.WriteHeader(http.StatusInternalServerError)
+		} else {
+			rec.WriteHeader(http.StatusOK)
+		}
+		return rec.Result(), nil
+	})
+
+	client := Retry(3, time.Millisecond)(rt)
+
+	req, _ := http.NewRequest(http.MethodPost, "http://example.invalid/", strings.NewReader("hello"))
+	if _, err := client.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(gotBodies) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(gotBodies))
+	}
+	for i, body := range gotBodies {
+		if body != "hello" {
+			t.Fatalf("attempt %d got body %q, want %q", i+1, body, "hello")
+		}
+	}
+}
+
+func TestRetryDoesNotRetryBodyWithoutGetBody(t *testing.T) {
+
+	attempts := 0
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		rec := httptest.NewRecorder()
+		rec.WriteHeader(http.StatusInternalServerError)
+		return rec.Result(), nil
+	})
+
+	client := Retry(3, time.Millisecond)(rt)
+
+	req, _ := http.NewRequest(http.MethodPost, "http://example.invalid/", strings.NewReader("hello"))
+	req.GetBody = nil // bodyを読み直す手段がないリクエストを模す
+
+	resp, err := client.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected the single 5xx response to be returned as-is, got %d", resp.StatusCode)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected no retry without GetBody, got %d attempts", attempts)
+	}
+}
+
+func TestRateLimitSpacesOutRequestsBeyondBurst(t *testing.T) {
+
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return httptest.NewRecorder().Result(), nil
+	})
+
+	client := RateLimit(10)(rt) // burst = 10, so the 11th request must wait
+
+	start := time.Now()
+	for i := 0; i < 11; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://example.invalid/", nil)
+		if _, err := client.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	elapsed := time.Since(start)
+
+	if elapsed < 50*time.Millisecond {
+		t.Fatalf("expected the 11th request to be delayed past the burst, elapsed=%v", elapsed)
+	}
+}
+
+func TestRateLimitDisabledForNonPositiveRps(t *testing.T) {
+
+	calls := 0
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return httptest.NewRecorder().Result(), nil
+	})
+
+	client := RateLimit(0)(rt)
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://example.invalid/", nil)
+		if _, err := client.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if calls != 100 {
+		t.Fatalf("expected 100 calls to reach the underlying RoundTripper, got %d", calls)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("expected RateLimit(0) to not throttle requests, elapsed=%v", elapsed)
+	}
+}
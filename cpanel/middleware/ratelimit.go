@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/naototty/conoha-vps/cpanel"
+)
+
+// rps（1秒あたりのリクエスト数）に基づくトークンバケット方式でリクエストを間引くRoundTripper。
+// バケットの容量（バースト時に連続で許可するリクエスト数）はrpsを切り上げた値を使う。
+// rpsが0以下の場合は間引きを行わない
+func RateLimit(rps float64) cpanel.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		if rps <= 0 {
+			return next
+		}
+
+		burst := math.Ceil(rps)
+		if burst < 1 {
+			burst = 1
+		}
+
+		return &rateLimitTransport{
+			next:   next,
+			rps:    rps,
+			burst:  burst,
+			tokens: burst,
+		}
+	}
+}
+
+type rateLimitTransport struct {
+	next  http.RoundTripper
+	rps   float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	t.mu.Lock()
+
+	now := time.Now()
+	if !t.last.IsZero() {
+		t.tokens += now.Sub(t.last).Seconds() * t.rps
+		if t.tokens > t.burst {
+			t.tokens = t.burst
+		}
+	}
+	t.last = now
+
+	if t.tokens < 1 {
+		wait := time.Duration((1 - t.tokens) / t.rps * float64(time.Second))
+		t.mu.Unlock()
+		time.Sleep(wait)
+
+		t.mu.Lock()
+		t.tokens = 0
+		t.last = time.Now()
+	} else {
+		t.tokens--
+	}
+
+	t.mu.Unlock()
+
+	return t.next.RoundTrip(req)
+}
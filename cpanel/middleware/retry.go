@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/naototty/conoha-vps/cpanel"
+)
+
+// 冪等なGETリクエストと5xxレスポンスをn回までリトライするRoundTripper
+func Retry(n int, backoff time.Duration) cpanel.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &retryTransport{next: next, n: n, backoff: backoff}
+	}
+}
+
+type retryTransport struct {
+	next    http.RoundTripper
+	n       int
+	backoff time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	var resp *http.Response
+	var err error
+
+	for attempt := 0; attempt <= t.n; attempt++ {
+
+		resp, err = t.next.RoundTrip(req)
+
+		// 冪等なGETは接続エラーでもリトライする。5xxはメソッドを問わずリトライする
+		retryable := (req.Method == http.MethodGet && err != nil) || (resp != nil && resp.StatusCode >= 500)
+
+		// ボディ付きのリクエストはGetBodyで読み直せない限りリトライできない
+		// （すでに読み切られたreq.Bodyをそのまま再送すると空/不完全な内容になる）
+		if retryable && req.Body != nil && req.GetBody == nil {
+			retryable = false
+		}
+
+		if !retryable || attempt == t.n {
+			return resp, err
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		time.Sleep(t.backoff)
+
+		if req.Body != nil {
+			body, gerr := req.GetBody()
+			if gerr != nil {
+				return resp, err
+			}
+			req.Body = body
+		}
+	}
+
+	return resp, err
+}
@@ -0,0 +1,93 @@
+package cpanel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// SaveSessionに保存するCookie情報
+// cookiejar.Cookiesが返すのはNameとValueのみのため、Path・Domain・Expiresは常にゼロ値で保存される
+type savedCookie struct {
+	Name    string    `json:"name"`
+	Value   string    `json:"value"`
+	Path    string    `json:"path"`
+	Domain  string    `json:"domain"`
+	Expires time.Time `json:"expires"`
+}
+
+// SaveSession/LoadSessionが読み書きするファイルフォーマット
+type sessionFile struct {
+	Cookies []savedCookie `json:"cookies"`
+	Values  url.Values    `json:"values"`
+}
+
+// cookiejarが保持しているCookieとhiddenパラメータをJSONファイルへ保存する
+func (b *BrowserInfo) SaveSession(path string) error {
+
+	sf := sessionFile{
+		Values: b.Values,
+	}
+
+	for _, cookie := range b.cookiejar.Cookies(b.cookieUrl()) {
+		sf.Cookies = append(sf.Cookies, savedCookie{
+			Name:    cookie.Name,
+			Value:   cookie.Value,
+			Path:    cookie.Path,
+			Domain:  cookie.Domain,
+			Expires: cookie.Expires,
+		})
+	}
+
+	data, err := json.Marshal(sf)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+// JSONファイルからCookieとhiddenパラメータを読み込み、cookiejarとValuesを復元する
+func (b *BrowserInfo) LoadSession(path string) error {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var sf sessionFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		return err
+	}
+
+	cookies := make([]*http.Cookie, 0, len(sf.Cookies))
+	for _, c := range sf.Cookies {
+		cookies = append(cookies, &http.Cookie{
+			Name:    c.Name,
+			Value:   c.Value,
+			Path:    c.Path,
+			Domain:  c.Domain,
+			Expires: c.Expires,
+		})
+	}
+
+	b.cookiejar.SetCookies(b.cookieUrl(), cookies)
+	b.Values = sf.Values
+
+	return nil
+}
+
+// セッションファイルを使うBrowserを構築する
+// ファイルが存在すればセッションを復元し、存在しなければそのまま何もしない
+func (b *Browser) WithSessionFile(path string) *Browser {
+
+	b.sessionFile = path
+
+	if _, err := os.Stat(path); err == nil {
+		b.BrowserInfo.LoadSession(path)
+	}
+
+	return b
+}
@@ -0,0 +1,61 @@
+package cpanel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveAndLoadSessionRoundTrips(t *testing.T) {
+
+	bi := &BrowserInfo{}
+	bi.InitializeDefault()
+	bi.FixSid("abc123")
+	bi.Values.Set("__VIEWSTATE", "state-1")
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := bi.SaveSession(path); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("session file not written: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("expected session file mode 0600, got %v", perm)
+	}
+
+	loaded := &BrowserInfo{}
+	loaded.InitializeDefault()
+	if err := loaded.LoadSession(path); err != nil {
+		t.Fatalf("LoadSession failed: %v", err)
+	}
+
+	if loaded.Sid() != "abc123" {
+		t.Fatalf("expected restored sid %q, got %q", "abc123", loaded.Sid())
+	}
+	if got := loaded.Values.Get("__VIEWSTATE"); got != "state-1" {
+		t.Fatalf("expected restored __VIEWSTATE %q, got %q", "state-1", got)
+	}
+}
+
+func TestWithSessionFileHydratesFromExistingFile(t *testing.T) {
+
+	bi := &BrowserInfo{}
+	bi.InitializeDefault()
+	bi.FixSid("xyz789")
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := bi.SaveSession(path); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	b := &Browser{BrowserInfo: &BrowserInfo{}}
+	b.BrowserInfo.InitializeDefault()
+	b.WithSessionFile(path)
+
+	if b.BrowserInfo.Sid() != "xyz789" {
+		t.Fatalf("expected WithSessionFile to hydrate sid, got %q", b.BrowserInfo.Sid())
+	}
+}